@@ -0,0 +1,117 @@
+package broker
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeAcknowledger records Ack/Nack calls so failDelivery's behavior can be
+// asserted without a live AMQP channel.
+type fakeAcknowledger struct {
+	acked  []uint64
+	nacked []nackCall
+}
+
+type nackCall struct {
+	tag      uint64
+	multiple bool
+	requeue  bool
+}
+
+func (f *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	f.acked = append(f.acked, tag)
+	return nil
+}
+
+func (f *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	f.nacked = append(f.nacked, nackCall{tag, multiple, requeue})
+	return nil
+}
+
+func (f *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return nil
+}
+
+func TestNextRetryClearRetries(t *testing.T) {
+	b := NewAMQPBroker()
+
+	if got := b.nextRetry("a"); got != 1 {
+		t.Fatalf("nextRetry(a) #1 = %d, want 1", got)
+	}
+	if got := b.nextRetry("a"); got != 2 {
+		t.Fatalf("nextRetry(a) #2 = %d, want 2", got)
+	}
+	if got := b.nextRetry("b"); got != 1 {
+		t.Fatalf("nextRetry(b) #1 = %d, want 1 (independent key)", got)
+	}
+
+	b.clearRetries("a")
+	if got := b.nextRetry("a"); got != 1 {
+		t.Fatalf("nextRetry(a) after clearRetries = %d, want 1", got)
+	}
+}
+
+func TestFailDeliveryRetryCap(t *testing.T) {
+	b := NewAMQPBroker()
+	sub := &amqpSubscriber{topics: []string{"jobs.build"}, maxRetries: 2, retryBackoff: 0}
+	ack := &fakeAcknowledger{}
+	delivery := amqp.Delivery{Acknowledger: ack, DeliveryTag: 1, RoutingKey: "jobs.build", MessageId: "msg-1"}
+
+	// Attempts 1 and 2 are within sub.maxRetries: requeue and do not clear the counter.
+	for attempt := 1; attempt <= sub.maxRetries; attempt++ {
+		b.failDelivery(sub, delivery, errFake)
+		if len(ack.nacked) != attempt {
+			t.Fatalf("after attempt %d: %d Nacks recorded, want %d", attempt, len(ack.nacked), attempt)
+		}
+		last := ack.nacked[len(ack.nacked)-1]
+		if !last.requeue {
+			t.Fatalf("attempt %d: requeue = false, want true (attempt <= maxRetries)", attempt)
+		}
+		if got := b.retries[retryKeyOf(delivery)]; got != attempt {
+			t.Fatalf("after attempt %d: retry counter = %d, want %d", attempt, got, attempt)
+		}
+	}
+
+	// The next failure exceeds maxRetries: dead-lettered (here: dropped, no dlx)
+	// and the counter is cleared so a later redelivery starts over at 1.
+	b.failDelivery(sub, delivery, errFake)
+	last := ack.nacked[len(ack.nacked)-1]
+	if last.requeue {
+		t.Fatalf("final attempt: requeue = true, want false (retry cap exceeded)")
+	}
+	if _, exists := b.retries[retryKeyOf(delivery)]; exists {
+		t.Fatalf("retry counter for %q still present after exceeding maxRetries", retryKeyOf(delivery))
+	}
+}
+
+var errFake = &testError{"handler failed"}
+
+type testError struct{ msg string }
+
+func (e *testError) Error() string { return e.msg }
+
+func TestBackoff(t *testing.T) {
+	b := NewAMQPBroker()
+	b.SetReconnectBackoff(time.Second, 30*time.Second)
+
+	cases := []struct {
+		previous         time.Duration
+		minNext, maxNext time.Duration
+	}{
+		{previous: 0, minNext: 500 * time.Millisecond, maxNext: time.Second},
+		{previous: time.Second, minNext: time.Second, maxNext: 2 * time.Second},
+		{previous: 20 * time.Second, minNext: 15 * time.Second, maxNext: 30 * time.Second},
+		{previous: time.Minute, minNext: 15 * time.Second, maxNext: 30 * time.Second},
+	}
+
+	for _, tc := range cases {
+		for i := 0; i < 20; i++ {
+			got := b.backoff(tc.previous)
+			if got < tc.minNext || got >= tc.maxNext {
+				t.Fatalf("backoff(%s) = %s, want in [%s, %s)", tc.previous, got, tc.minNext, tc.maxNext)
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package broker
+
+import (
+	"context"
+	"time"
+)
+
+// Message is a normalized, transport-agnostic event delivered by a Broker.
+// Actions work against this type instead of a transport-specific delivery
+// so the same action code runs unmodified across AMQP, NATS, Kafka, etc.
+type Message struct {
+	Topic   string
+	Headers map[string]string
+	Body    []byte
+}
+
+// Handler processes a single Message delivered by a Subscriber.
+type Handler func(ctx context.Context, msg *Message) error
+
+// Subscriber represents an active subscription to one or more topics.
+type Subscriber interface {
+	Topics() []string
+	Unsubscribe() error
+}
+
+// Options carries the transport-specific routing a Subscribe/Publish call binds to.
+// Brokers without the concept of exchanges (NATS, Kafka, Redis Streams) ignore it.
+type Options struct {
+	// Exchange is the exchange/stream the topic is declared against.
+	// Defaults to the broker's own default exchange when empty.
+	Exchange string
+
+	// ExchangeType is the exchange kind (topic, direct, fanout, headers, ...).
+	// Defaults to "topic" when empty.
+	ExchangeType string
+
+	// PoolSize bounds the number of deliveries a Subscribe call processes
+	// concurrently. Defaults to 1 when unset.
+	PoolSize int
+
+	// MaxRetries is how many times a failing delivery is redelivered before
+	// it is routed to DeadLetterExchange (or dropped, if that is empty).
+	MaxRetries int
+
+	// RetryBackoff is how long to wait before redelivering a failed message.
+	RetryBackoff time.Duration
+
+	// DeadLetterExchange, if set, receives deliveries that exhausted MaxRetries,
+	// tagged with failure metadata headers.
+	DeadLetterExchange string
+}
+
+// Option mutates Options. Use WithExchange to bind a Subscribe/Publish call to a
+// non-default exchange, or WithPoolSize/WithRetry/WithDeadLetter to configure a
+// Subscribe call's worker pool and failure handling.
+type Option func(*Options)
+
+// WithExchange binds a Subscribe/Publish call to a specific exchange and exchange type.
+func WithExchange(name string, kind string) Option {
+	return func(o *Options) {
+		o.Exchange = name
+		o.ExchangeType = kind
+	}
+}
+
+// WithPoolSize bounds how many deliveries a Subscribe call processes concurrently.
+func WithPoolSize(size int) Option {
+	return func(o *Options) {
+		o.PoolSize = size
+	}
+}
+
+// WithRetry configures how many times, and after what backoff, a failing
+// delivery is redelivered before it is dead-lettered or dropped.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(o *Options) {
+		o.MaxRetries = maxRetries
+		o.RetryBackoff = backoff
+	}
+}
+
+// WithDeadLetter routes deliveries that exhaust their retries to exchange.
+func WithDeadLetter(exchange string) Option {
+	return func(o *Options) {
+		o.DeadLetterExchange = exchange
+	}
+}
+
+func newOptions(opts ...Option) *Options {
+	o := &Options{ExchangeType: "topic", PoolSize: 1, RetryBackoff: time.Second}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.PoolSize <= 0 {
+		o.PoolSize = 1
+	}
+	if o.ExchangeType == "" {
+		o.ExchangeType = "topic"
+	}
+	return o
+}
+
+// Broker abstracts a pub/sub message transport (AMQP, NATS, Kafka, Redis
+// Streams, ...) so the reactor can dispatch events the same way regardless
+// of what is carrying them.
+type Broker interface {
+	// Connect establishes the transport connection and blocks, delivering
+	// messages to subscribers, until ctx is done or Disconnect is called.
+	Connect(ctx context.Context) error
+
+	// Disconnect tears down the transport connection, making Connect return.
+	Disconnect() error
+
+	// Subscribe registers handler to be called for every message matching any of
+	// topics (routing/binding keys), returning a Subscriber that can later be
+	// used to Unsubscribe. Each Subscribe call gets its own dedicated
+	// queue/consumer shared by all of topics, so a delivery is only ever
+	// dispatched to the handler whose subscription produced it.
+	Subscribe(topics []string, handler Handler, opts ...Option) (Subscriber, error)
+
+	// Publish sends msg on topic.
+	Publish(topic string, msg *Message, opts ...Option) error
+}
@@ -0,0 +1,34 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+)
+
+// KafkaBroker is a stub Broker for Apache Kafka topics. It satisfies the
+// Broker interface so Reactor can be wired up against it, but the actual
+// producer/consumer wiring is not implemented yet.
+type KafkaBroker struct {
+	brokers []string
+}
+
+// NewKafkaBroker creates a Broker for the given Kafka broker addresses.
+func NewKafkaBroker(brokers []string) *KafkaBroker {
+	return &KafkaBroker{brokers: brokers}
+}
+
+func (b *KafkaBroker) Connect(ctx context.Context) error {
+	return fmt.Errorf("kafka broker is not implemented yet")
+}
+
+func (b *KafkaBroker) Disconnect() error {
+	return fmt.Errorf("kafka broker is not implemented yet")
+}
+
+func (b *KafkaBroker) Subscribe(topics []string, handler Handler, opts ...Option) (Subscriber, error) {
+	return nil, fmt.Errorf("kafka broker is not implemented yet")
+}
+
+func (b *KafkaBroker) Publish(topic string, msg *Message, opts ...Option) error {
+	return fmt.Errorf("kafka broker is not implemented yet")
+}
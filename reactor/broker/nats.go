@@ -0,0 +1,122 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	wzlib_logger "github.com/infra-whizz/wzlib/logger"
+	"github.com/nats-io/nats.go"
+
+	"github.com/mikak/jenkobs/reactor/metrics"
+)
+
+// NATSBroker is a Broker backed by a NATS core pub/sub subject.
+type NATSBroker struct {
+	url  string
+	conn *nats.Conn
+	ctx  context.Context
+
+	mtx  sync.Mutex
+	subs []*nats.Subscription
+
+	wzlib_logger.WzLogger
+}
+
+// NewNATSBroker creates a Broker talking to the NATS server at url
+// (e.g. "nats://localhost:4222").
+func NewNATSBroker(url string) *NATSBroker {
+	b := new(NATSBroker)
+	b.url = url
+	return b
+}
+
+type natsSubscriber struct {
+	topics []string
+	subs   []*nats.Subscription
+}
+
+func (s *natsSubscriber) Topics() []string {
+	return s.topics
+}
+
+func (s *natsSubscriber) Unsubscribe() error {
+	for _, sub := range s.subs {
+		if err := sub.Unsubscribe(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Connect establishes the NATS connection. It blocks until ctx is done or the
+// connection is closed (e.g. by Disconnect). ctx is also threaded through to
+// every handler invoked by an active subscription.
+func (b *NATSBroker) Connect(ctx context.Context) error {
+	closed := make(chan struct{})
+	conn, err := nats.Connect(b.url, nats.ClosedHandler(func(*nats.Conn) { close(closed) }))
+	if err != nil {
+		b.GetLogger().Errorf("Error connecting to NATS at %s: %s", b.url, err.Error())
+		return err
+	}
+	b.conn = conn
+	b.ctx = ctx
+	b.GetLogger().Infof("Connected to NATS at %s", b.url)
+	metrics.BrokerConnected.Set(1)
+
+	select {
+	case <-ctx.Done():
+	case <-closed:
+	}
+	metrics.BrokerConnected.Set(0)
+	return nil
+}
+
+// Disconnect closes the NATS connection.
+func (b *NATSBroker) Disconnect() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for _, sub := range b.subs {
+		sub.Unsubscribe()
+	}
+	if b.conn != nil {
+		b.conn.Close()
+	}
+	return nil
+}
+
+// Subscribe registers handler to be called for every message published on any
+// of topics.
+func (b *NATSBroker) Subscribe(topics []string, handler Handler, opts ...Option) (Subscriber, error) {
+	if b.conn == nil {
+		return nil, fmt.Errorf("not connected to NATS")
+	}
+	cb := func(m *nats.Msg) {
+		metrics.DeliveriesReceived.Inc()
+		ctx := b.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		handler(ctx, &Message{Topic: m.Subject, Body: m.Data})
+	}
+	subs := make([]*nats.Subscription, 0, len(topics))
+	for _, topic := range topics {
+		sub, err := b.conn.Subscribe(topic, cb)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	b.mtx.Lock()
+	b.subs = append(b.subs, subs...)
+	b.mtx.Unlock()
+	return &natsSubscriber{topics: topics, subs: subs}, nil
+}
+
+// Publish sends msg on the NATS subject topic.
+func (b *NATSBroker) Publish(topic string, msg *Message, opts ...Option) error {
+	if b.conn == nil {
+		return fmt.Errorf("not connected to NATS")
+	}
+	return b.conn.Publish(topic, msg.Body)
+}
@@ -0,0 +1,34 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+)
+
+// RedisStreamsBroker is a stub Broker for Redis Streams. It satisfies the
+// Broker interface so Reactor can be wired up against it, but the actual
+// XADD/XREAD wiring is not implemented yet.
+type RedisStreamsBroker struct {
+	addr string
+}
+
+// NewRedisStreamsBroker creates a Broker for the Redis instance at addr.
+func NewRedisStreamsBroker(addr string) *RedisStreamsBroker {
+	return &RedisStreamsBroker{addr: addr}
+}
+
+func (b *RedisStreamsBroker) Connect(ctx context.Context) error {
+	return fmt.Errorf("redis streams broker is not implemented yet")
+}
+
+func (b *RedisStreamsBroker) Disconnect() error {
+	return fmt.Errorf("redis streams broker is not implemented yet")
+}
+
+func (b *RedisStreamsBroker) Subscribe(topics []string, handler Handler, opts ...Option) (Subscriber, error) {
+	return nil, fmt.Errorf("redis streams broker is not implemented yet")
+}
+
+func (b *RedisStreamsBroker) Publish(topic string, msg *Message, opts ...Option) error {
+	return fmt.Errorf("redis streams broker is not implemented yet")
+}
@@ -0,0 +1,66 @@
+// Package metrics exposes the reactor's Prometheus instrumentation: delivery
+// and dispatch counters, per-action success/failure/latency, and the current
+// broker connection state.
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// DeliveriesReceived counts every message delivered to the reactor by its broker.
+	DeliveriesReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "jenkobs_reactor_deliveries_received_total",
+		Help: "Total number of broker deliveries received by the reactor.",
+	})
+
+	// ActionDispatched counts deliveries handed off to a given action.
+	ActionDispatched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "jenkobs_reactor_action_dispatched_total",
+		Help: "Total number of deliveries dispatched to an action.",
+	}, []string{"project", "type"})
+
+	// ActionSuccess counts action runs that returned without error.
+	ActionSuccess = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "jenkobs_reactor_action_success_total",
+		Help: "Total number of action runs that completed successfully.",
+	}, []string{"project", "type"})
+
+	// ActionFailure counts action runs that returned an error.
+	ActionFailure = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "jenkobs_reactor_action_failure_total",
+		Help: "Total number of action runs that returned an error.",
+	}, []string{"project", "type"})
+
+	// ActionDuration tracks how long an action's OnMessage call takes to return.
+	ActionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "jenkobs_reactor_action_duration_seconds",
+		Help: "Action execution latency in seconds.",
+	}, []string{"project", "type"})
+
+	// BrokerConnected is 1 while the reactor holds a live broker connection, 0 otherwise.
+	BrokerConnected = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "jenkobs_reactor_broker_connected",
+		Help: "1 if the reactor is currently connected to its broker, 0 otherwise.",
+	})
+)
+
+// Serve starts an HTTP server exposing /metrics on addr. It returns
+// immediately; call Shutdown on the returned server to stop it.
+func Serve(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go srv.ListenAndServe()
+	return srv
+}
+
+// Shutdown gracefully stops an HTTP server started by Serve.
+func Shutdown(ctx context.Context, srv *http.Server) error {
+	return srv.Shutdown(ctx)
+}
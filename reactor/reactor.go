@@ -1,26 +1,25 @@
 package jenkobs_reactor
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
 	wzlib_logger "github.com/infra-whizz/wzlib/logger"
-	"github.com/streadway/amqp"
+
+	"github.com/mikak/jenkobs/reactor/broker"
+	"github.com/mikak/jenkobs/reactor/metrics"
+	"github.com/mikak/jenkobs/reactor/telemetry"
 )
 
 type Reactor struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	queue   amqp.Queue
-	actions []ReactorAction
-
-	user     string
-	password string
-	fqdn     string
-	port     int
+	broker     broker.Broker
+	actions    []ReactorAction
+	metricsSrv string
 
 	wzlib_logger.WzLogger
 }
@@ -31,64 +30,35 @@ func NewReactor() *Reactor {
 	return rtr
 }
 
-// SetAMQPDial string
-func (rtr *Reactor) SetAMQPDial(user string, password string, fqdn string, port int) *Reactor {
-	rtr.user = user
-	rtr.password = password
-	rtr.fqdn = fqdn
-	rtr.port = port
+// SetBroker configures the message broker (AMQP, NATS, Kafka, Redis Streams, ...)
+// the reactor consumes events from.
+func (rtr *Reactor) SetBroker(b broker.Broker) *Reactor {
+	rtr.broker = b
 	return rtr
 }
 
-func (rtr *Reactor) connectAMQP() error {
-	if rtr.user == "" || rtr.fqdn == "" {
-		err := fmt.Errorf("Error connecting to the AMQP server: user or FQDN are missing")
-		rtr.GetLogger().Error(err.Error())
-		return err
-	}
-	var err error
-	var connstr string
-	if rtr.port > 0 {
-		connstr = fmt.Sprintf("amqps://%s:%s@%s:%d/", rtr.user, rtr.password, rtr.fqdn, rtr.port)
-	} else {
-		connstr = fmt.Sprintf("amqps://%s:%s@%s/", rtr.user, rtr.password, rtr.fqdn)
-	}
-	rtr.conn, err = amqp.Dial(connstr)
-	if err != nil {
-		rtr.GetLogger().Errorf("Error connecting to the AMQP server: %s", err.Error())
-		return err
-	} else {
-		rtr.GetLogger().Infof("Connected to AMQP at %s", rtr.fqdn)
-	}
-
-	// Setup channel
-	rtr.channel, err = rtr.conn.Channel()
-	err = rtr.channel.ExchangeDeclarePassive("pubsub", "topic", true, false, false, false, nil)
-	if err != nil {
-		rtr.GetLogger().Errorf("Error creating AMQP channel: %s", err.Error())
-		return err
-	} else {
-		rtr.GetLogger().Infof("Created AMQP channel")
-	}
+// SetMetricsAddr configures the address (e.g. ":9090") Run() serves the
+// Prometheus /metrics endpoint on. Leave empty to disable it.
+func (rtr *Reactor) SetMetricsAddr(addr string) *Reactor {
+	rtr.metricsSrv = addr
+	return rtr
+}
 
-	// Setup queue
-	rtr.queue, err = rtr.channel.QueueDeclare("", false, false, true, false, nil)
-	if err != nil {
-		rtr.GetLogger().Errorf("Error setting up queue: %s", err.Error())
-		return err
-	} else {
-		rtr.GetLogger().Infof("Default queue declared")
-	}
+// SetAMQPDial configures the reactor to use an AMQP topic exchange as its broker.
+func (rtr *Reactor) SetAMQPDial(user string, password string, fqdn string, port int) *Reactor {
+	return rtr.SetBroker(broker.NewAMQPBroker().SetDial(user, password, fqdn, port))
+}
 
-	if err = rtr.channel.QueueBind(rtr.queue.Name, "#", "pubsub", false, nil); err != nil {
-		rtr.GetLogger().Errorf("Error binding queue '%s' to the channel: %s", rtr.queue.Name, err.Error())
-		return err
-	} else {
-		rtr.GetLogger().Infof("Bound queue '%s' to the channel", rtr.queue.Name)
-	}
+const (
+	defaultBindingExchange     = "pubsub"
+	defaultBindingExchangeType = "topic"
+	defaultPoolSize            = 1
+	defaultRetryBackoff        = time.Second
+)
 
-	return nil
-}
+// defaultRoutingKeys matches the catch-all binding the reactor used before
+// actions could declare their own exchange/routing keys.
+var defaultRoutingKeys = []string{"#"}
 
 func (rtr *Reactor) getAction(actionSet map[string]interface{}) *ActionInfo {
 	// Always only one element anyways
@@ -107,12 +77,19 @@ func (rtr *Reactor) getAction(actionSet map[string]interface{}) *ActionInfo {
 		}
 
 		action := &ActionInfo{
-			Project:      key,
-			Package:      actionData["package"].(string),
-			Status:       actionData["status"].(string),
-			Architecture: actionData["arch"].(string),
-			Params:       params,
-			Type:         actionType,
+			Project:            key,
+			Package:            actionData["package"].(string),
+			Status:             actionData["status"].(string),
+			Architecture:       actionData["arch"].(string),
+			Params:             params,
+			Type:               actionType,
+			Exchange:           stringOrDefault(actionData["exchange"], defaultBindingExchange),
+			ExchangeType:       stringOrDefault(actionData["exchange_type"], defaultBindingExchangeType),
+			RoutingKeys:        routingKeysOf(actionData),
+			PoolSize:           intOrDefault(actionData["pool_size"], defaultPoolSize),
+			MaxRetries:         intOrDefault(actionData["retry_count"], 0),
+			RetryBackoff:       durationOrDefault(actionData["retry_backoff"], defaultRetryBackoff),
+			DeadLetterExchange: stringOrDefault(actionData["dlx"], ""),
 		}
 		if actionType == "" {
 			rtr.GetLogger().Warnf("Action on project '%s' with package '%s' does not have defined action type, skipping",
@@ -125,6 +102,60 @@ func (rtr *Reactor) getAction(actionSet map[string]interface{}) *ActionInfo {
 	return nil
 }
 
+func stringOrDefault(value interface{}, def string) string {
+	if s, ok := value.(string); ok && s != "" {
+		return s
+	}
+	return def
+}
+
+func intOrDefault(value interface{}, def int) int {
+	if i, ok := value.(int); ok {
+		return i
+	}
+	return def
+}
+
+// durationOrDefault parses a YAML duration string (e.g. "500ms", "5s"), falling
+// back to def when value is absent or malformed.
+func durationOrDefault(value interface{}, def time.Duration) time.Duration {
+	if s, ok := value.(string); ok {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+// routingKeysOf reads an action's "routing_keys" (or the "binding_keys" alias),
+// accepting either a single scalar or a YAML sequence, and falls back to the
+// reactor's historical catch-all binding when neither is set.
+func routingKeysOf(actionData map[interface{}]interface{}) []string {
+	raw, ok := actionData["routing_keys"]
+	if !ok {
+		raw, ok = actionData["binding_keys"]
+	}
+	if !ok {
+		return defaultRoutingKeys
+	}
+
+	switch keys := raw.(type) {
+	case string:
+		return []string{keys}
+	case []interface{}:
+		routingKeys := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if s, ok := key.(string); ok {
+				routingKeys = append(routingKeys, s)
+			}
+		}
+		if len(routingKeys) > 0 {
+			return routingKeys
+		}
+	}
+	return defaultRoutingKeys
+}
+
 // LoadConfig of the reactor
 func (rtr *Reactor) LoadActions(actionsCfgPath string) *Reactor {
 	content, err := ioutil.ReadFile(actionsCfgPath)
@@ -163,40 +194,66 @@ func (rtr *Reactor) LoadActions(actionsCfgPath string) *Reactor {
 	return rtr
 }
 
-func (rtr *Reactor) onDelivery(delivery amqp.Delivery) error {
-	for _, action := range rtr.actions {
-		rtr.GetLogger().Debugf("Processing action %s", action.GetAction().Type)
-		go action.OnMessage(&delivery)
-	}
-	return nil
-}
+// actionHandler returns a broker.Handler dispatching only to action, for a
+// subscription bound to action's own exchange/routing key. It records the
+// dispatch/success/failure/latency metrics and traces the call with a span
+// extracted from the message headers.
+func (rtr *Reactor) actionHandler(action ReactorAction) broker.Handler {
+	info := action.GetAction()
+	return func(ctx context.Context, msg *broker.Message) error {
+		ctx, span := telemetry.StartSpan(ctx, "reactor.action.on_message", msg.Headers)
+		defer span.End()
 
-func (rtr *Reactor) consume() error {
-	msgChannel, err := rtr.channel.Consume(rtr.queue.Name, "", true, false, false, false, nil)
-	if err != nil {
+		rtr.GetLogger().Debugf("Processing action %s", info.Type)
+		metrics.ActionDispatched.WithLabelValues(info.Project, info.Type).Inc()
+
+		start := time.Now()
+		err := action.OnMessage(ctx, msg)
+		metrics.ActionDuration.WithLabelValues(info.Project, info.Type).Observe(time.Since(start).Seconds())
+		if err != nil {
+			metrics.ActionFailure.WithLabelValues(info.Project, info.Type).Inc()
+			span.RecordError(err)
+		} else {
+			metrics.ActionSuccess.WithLabelValues(info.Project, info.Type).Inc()
+		}
 		return err
 	}
-	looper := make(chan bool)
+}
 
-	go func() {
-		rtr.GetLogger().Debug("Listening to the events...")
-		for delivery := range msgChannel {
-			go rtr.onDelivery(delivery)
-		}
-	}()
+// Run the reactor: start the metrics endpoint (if configured), bind every
+// loaded action to its own exchange/routing keys on the broker, and block,
+// (re)connecting as the broker sees fit, until ctx is done or Stop() is called.
+func (rtr *Reactor) Run(ctx context.Context) error {
+	if rtr.broker == nil {
+		return fmt.Errorf("no broker configured for the reactor")
+	}
 
-	<-looper
-	return nil
-}
+	if rtr.metricsSrv != "" {
+		srv := metrics.Serve(rtr.metricsSrv)
+		defer metrics.Shutdown(context.Background(), srv)
+		rtr.GetLogger().Infof("Serving Prometheus metrics on %s/metrics", rtr.metricsSrv)
+	}
 
-// Run the reactor
-func (rtr *Reactor) Run() error {
-	if err := rtr.connectAMQP(); err == nil {
-		defer rtr.conn.Close()
-		if err := rtr.consume(); err != nil {
-			rtr.GetLogger().Errorf("Error consuming messages: %s", err.Error())
+	for _, action := range rtr.actions {
+		info := action.GetAction()
+		opts := []broker.Option{
+			broker.WithExchange(info.Exchange, info.ExchangeType),
+			broker.WithPoolSize(info.PoolSize),
+			broker.WithRetry(info.MaxRetries, info.RetryBackoff),
+			broker.WithDeadLetter(info.DeadLetterExchange),
+		}
+		if _, err := rtr.broker.Subscribe(info.RoutingKeys, rtr.actionHandler(action), opts...); err != nil {
+			rtr.GetLogger().Errorf("Error subscribing action for project '%s' to '%s'/%v: %s",
+				info.Project, info.Exchange, info.RoutingKeys, err.Error())
+			return err
 		}
 	}
+	return rtr.broker.Connect(ctx)
+}
 
-	return nil
+// Stop the reactor, disconnecting the broker and returning from Run().
+func (rtr *Reactor) Stop() {
+	if rtr.broker != nil {
+		rtr.broker.Disconnect()
+	}
 }
@@ -0,0 +1,54 @@
+package jenkobs_reactor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoutingKeysOf(t *testing.T) {
+	cases := []struct {
+		name       string
+		actionData map[interface{}]interface{}
+		want       []string
+	}{
+		{
+			name:       "missing falls back to the catch-all binding",
+			actionData: map[interface{}]interface{}{},
+			want:       defaultRoutingKeys,
+		},
+		{
+			name:       "single scalar routing_keys",
+			actionData: map[interface{}]interface{}{"routing_keys": "jobs.build"},
+			want:       []string{"jobs.build"},
+		},
+		{
+			name:       "sequence of routing_keys",
+			actionData: map[interface{}]interface{}{"routing_keys": []interface{}{"jobs.build", "jobs.test"}},
+			want:       []string{"jobs.build", "jobs.test"},
+		},
+		{
+			name:       "binding_keys alias used when routing_keys is absent",
+			actionData: map[interface{}]interface{}{"binding_keys": "jobs.deploy"},
+			want:       []string{"jobs.deploy"},
+		},
+		{
+			name:       "routing_keys takes precedence over binding_keys",
+			actionData: map[interface{}]interface{}{"routing_keys": "jobs.build", "binding_keys": "jobs.deploy"},
+			want:       []string{"jobs.build"},
+		},
+		{
+			name:       "sequence with no string entries falls back to the catch-all binding",
+			actionData: map[interface{}]interface{}{"routing_keys": []interface{}{1, 2}},
+			want:       defaultRoutingKeys,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := routingKeysOf(tc.actionData)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("routingKeysOf(%v) = %v, want %v", tc.actionData, got, tc.want)
+			}
+		})
+	}
+}
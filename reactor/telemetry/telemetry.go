@@ -0,0 +1,45 @@
+// Package telemetry wraps message handling in OpenTelemetry spans, extracting
+// the trace context propagated in broker message headers so a Jenkins/OBS
+// event can be traced end to end through the reactor and into its actions.
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("jenkobs_reactor")
+
+// headerCarrier adapts a broker.Message's string headers to otel's TextMapCarrier.
+type headerCarrier map[string]string
+
+func (c headerCarrier) Get(key string) string {
+	return c[key]
+}
+
+func (c headerCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// StartSpan extracts any trace context carried in headers and starts a child
+// span named name. Callers must End() the returned span.
+func StartSpan(ctx context.Context, name string, headers map[string]string) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier(headers))
+	return tracer.Start(ctx, name)
+}
+
+// Inject writes the current trace context from ctx into headers, so it can be
+// carried across a Publish call on any broker.
+func Inject(ctx context.Context, headers map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(headers))
+}